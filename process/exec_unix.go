@@ -0,0 +1,47 @@
+//go:build !windows
+// +build !windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// setProcessGroup configures cmd to run as the leader of its own process
+// group, so signalProcessGroup can later reach any grandchildren the script
+// spawned, not just the direct child exec.Cmd knows about.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup sends sig to the process group led by pid.
+func signalProcessGroup(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return nil
+	}
+
+	return syscall.Kill(-pid, s)
+}
+
+// winsize mirrors the kernel's struct winsize, used with TIOCSWINSZ to
+// report a pty's dimensions in character cells.
+type winsize struct {
+	Rows, Cols, X, Y uint16
+}
+
+// setWindowSize issues a TIOCSWINSZ ioctl against fd to update a pty's
+// window size.
+func setWindowSize(fd uintptr, rows, cols uint16) error {
+	ws := &winsize{Rows: rows, Cols: cols}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(ws)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
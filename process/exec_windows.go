@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no equivalent of POSIX
+// process groups for signalling.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup is a no-op on Windows; Kill falls back to TASKKILL
+// against the single PID instead.
+func signalProcessGroup(pid int, sig os.Signal) error {
+	return nil
+}
+
+// setWindowSize is a no-op on Windows: there's no pty/ioctl equivalent, and
+// WindowSize already only calls this when PTY is enabled, which isn't
+// supported here either.
+func setWindowSize(fd uintptr, rows, cols uint16) error {
+	return nil
+}
@@ -5,7 +5,7 @@ package process
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -32,32 +32,197 @@ type Process struct {
 	ExitStatus string
 
 	// For every line in the process output, this callback will be called
-	// with the contents of the line if its filter returns true
-	LineCallback       func(string)
+	// with the contents of the line and its origin stream if its filter
+	// returns true
+	LineCallback       func(string, Stream)
 	LinePreProcessor   func(string) string
 	LineCallbackFilter func(string) bool
 
+	// Stdin, if set, is streamed into the process (or the pty master, when
+	// PTY is enabled) for interactive jobs like shells and debuggers.
+	Stdin io.Reader
+
+	// StdoutWriter and StderrWriter, if set, receive a tee'd copy of the
+	// process output in addition to the internal line buffer. In PTY mode
+	// both stdout and stderr are combined, so only StdoutWriter is used.
+	StdoutWriter io.Writer
+	StderrWriter io.Writer
+
+	// Resize receives window size updates (e.g. forwarded SIGWINCH events
+	// from an SSH or websocket session) to apply to the pty while PTY mode
+	// is active.
+	Resize chan WindowSize
+
+	// SplitStderr gives stderr its own pipe and scanner instead of sharing
+	// stdout's, so each LineEvent can be tagged with its origin stream. It
+	// has no effect in PTY mode, where the pty always combines the two.
+	SplitStderr bool
+
+	// ShutdownPolicy controls how Kill, and a canceled StartContext,
+	// escalate signals. It defaults to SIGTERM, a 10 second grace period,
+	// then SIGKILL; see defaultShutdownPolicy.
+	ShutdownPolicy ShutdownPolicy
+
+	// NoOutputTimeout, if set, triggers stall handling once that long has
+	// passed since the last line was scanned from the process: a SIGQUIT
+	// is sent to try to capture a Go-style goroutine dump (see
+	// HangDiagnostics), then ShutdownPolicy's escalation ladder takes over.
+	// A process with SIGQUIT blocked is sent SIGKILL instead.
+	NoOutputTimeout time.Duration
+
 	// Running is stored as an int32 so we can use atomic operations to
 	// set/get it (it's accessed by multiple goroutines)
 	running int32
 
+	// lastOutputAt is a UnixNano timestamp of the last dispatched
+	// LineEvent, used by the stall monitor to measure NoOutputTimeout.
+	lastOutputAt int64
+
 	// The underlying command that is executed
 	command *exec.Cmd
 
-	// buffer is a used to buffer output when we are prefixing timestamps
-	buffer bytes.Buffer
-
-	// locker for data races on the buffer
-	bufferLock sync.Mutex
+	// The pty master, set when PTY is enabled, used to service WindowSize
+	ptyFile *os.File
+
+	// done is closed once the process has exited, so StartContext's
+	// context-watching goroutine can stop waiting on ctx.Done().
+	done chan struct{}
+
+	// seq is a monotonic counter stamped onto every LineEvent so that
+	// consumers reading split-out streams can reconstruct the original
+	// interleaving order.
+	seq uint64
+
+	// dispatchLock serializes dispatch end to end, from Seq assignment
+	// through handing the LineEvent to every sink, so that with
+	// SplitStderr on (where stdout and stderr are scanned by separate
+	// goroutines) a later-Seq line can never win the race into a sink
+	// ahead of an earlier one.
+	dispatchLock sync.Mutex
+
+	// sinks receive every LineEvent scanned from the process output. The
+	// first entry is always defaultSink, which backs Output().
+	sinks       []OutputSink
+	sinksLock   sync.Mutex
+	defaultSink *bufferSink
+
+	// hangDiagnostics holds whatever output was captured in the window
+	// after a NoOutputTimeout-triggered SIGQUIT, surfaced via
+	// HangDiagnostics().
+	hangDiagnostics     []byte
+	hangDiagnosticsLock sync.Mutex
 
 	// conditions to block on, see See http://openmymind.net/Condition-Variables/
 	startedCond *sync.Cond
 }
 
+// hangDiagnosticsWindow is how long we give a stalled process to flush a
+// SIGQUIT-triggered goroutine dump to stderr before moving on to the
+// shutdown escalation ladder.
+const hangDiagnosticsWindow = 2 * time.Second
+
+// WindowSize describes a terminal's dimensions in character cells.
+type WindowSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// ShutdownPolicy describes the escalation ladder Kill (and a canceled
+// StartContext) walk to stop a process: GracefulSignal is sent first, then
+// each of EscalationSignals in turn, waiting GracePeriod between each step
+// for the process to exit before moving on.
+type ShutdownPolicy struct {
+	GracefulSignal    os.Signal
+	GracePeriod       time.Duration
+	EscalationSignals []os.Signal
+}
+
+// defaultShutdownPolicy matches the historical behaviour of Kill: a SIGTERM,
+// a 10 second grace period, then a SIGKILL.
+func defaultShutdownPolicy() ShutdownPolicy {
+	return ShutdownPolicy{
+		GracefulSignal:    syscall.SIGTERM,
+		GracePeriod:       10 * time.Second,
+		EscalationSignals: []os.Signal{syscall.SIGKILL},
+	}
+}
+
 func NewProcess() *Process {
-	return &Process{
-		startedCond: &sync.Cond{L: &sync.Mutex{}},
+	p := &Process{
+		startedCond:    &sync.Cond{L: &sync.Mutex{}},
+		ShutdownPolicy: defaultShutdownPolicy(),
+		done:           make(chan struct{}),
 	}
+
+	p.defaultSink = newBufferSink(p)
+	p.sinks = []OutputSink{p.defaultSink}
+
+	return p
+}
+
+// AddSink registers an additional OutputSink that every LineEvent scanned
+// from the process output will be written to, alongside the default
+// in-memory sink backing Output().
+func (p *Process) AddSink(sink OutputSink) {
+	p.sinksLock.Lock()
+	defer p.sinksLock.Unlock()
+	p.sinks = append(p.sinks, sink)
+}
+
+// dispatch writes evt to every registered sink, logging (rather than
+// failing the process) if a sink returns an error. Seq assignment through
+// sink delivery is one critical section under dispatchLock, so concurrent
+// callers (e.g. the separate stdout/stderr scanners under SplitStderr)
+// always reach sinks in Seq order, not whichever order their goroutines
+// happen to win the race.
+func (p *Process) dispatch(evt LineEvent) {
+	p.dispatchLock.Lock()
+	defer p.dispatchLock.Unlock()
+
+	atomic.StoreInt64(&p.lastOutputAt, time.Now().UnixNano())
+	evt.Seq = atomic.AddUint64(&p.seq, 1)
+
+	p.sinksLock.Lock()
+	sinks := make([]OutputSink, len(p.sinks))
+	copy(sinks, p.sinks)
+	p.sinksLock.Unlock()
+
+	for _, sink := range sinks {
+		if err := sink.WriteLine(evt); err != nil {
+			logger.Error("[Process] Output sink failed to write line: %v", err)
+		}
+	}
+}
+
+// scanLines reads lines from reader, tags each with stream, and dispatches
+// it to the registered sinks. It runs in its own goroutine and adds itself
+// to waitGroup.
+func (p *Process) scanLines(reader io.Reader, stream Stream, waitGroup *sync.WaitGroup) {
+	scanner := bufio.NewScanner(reader)
+
+	waitGroup.Add(1)
+
+	go func() {
+		defer waitGroup.Done()
+
+		// We scan line by line so that we can run our various processors, currently this buffers the entire
+		// output in memory and then an asynchronous process reads it in chunks
+		logger.Debug("[LineScanner] Starting to read %s lines", stream)
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			p.dispatch(LineEvent{
+				Time:     time.Now().UTC(),
+				Stream:   stream,
+				Bytes:    []byte(line),
+				IsHeader: headerExpansionRegex.MatchString(p.LinePreProcessor(line)),
+			})
+		}
+
+		if err := scanner.Err(); err != nil {
+			logger.Debug("[LineScanner] Error from %s scanner: %v", stream, err)
+		}
+	}()
 }
 
 // If you change header parsing here make sure to change it in the
@@ -65,16 +230,30 @@ func NewProcess() *Process {
 
 var headerExpansionRegex = regexp.MustCompile("^(?:\\^\\^\\^\\s+\\+\\+\\+)$")
 
-// Start the process and block until it finishes
-func (p *Process) Start() error {
+// StartContext starts the process and blocks until it finishes. If ctx is
+// canceled first, it walks the ShutdownPolicy escalation ladder against the
+// process group and keeps waiting for the process to exit, matching the
+// cancellation semantics of exec.CommandContext.
+func (p *Process) StartContext(ctx context.Context) error {
 	p.startedCond.L.Lock()
 
+	// Match exec.CommandContext: if ctx is already done, don't start at
+	// all, rather than starting the process only to shut it straight down.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	args, err := shellwords.Parse(p.Script)
 	if err != nil {
 		return err
 	}
 
 	p.command = exec.Command(args[0], args[1:]...)
+	setProcessGroup(p.command)
+
+	// Closing this lets the context-watching goroutine below stop waiting
+	// on ctx.Done(), on every return path, not just the successful one.
+	defer close(p.done)
 
 	// Copy the current processes ENV and merge in the new ones. We do this
 	// so the sub process gets PATH and stuff. We merge our path in over
@@ -85,113 +264,58 @@ func (p *Process) Start() error {
 
 	lineReaderPipe, lineWriterPipe := io.Pipe()
 
+	// When SplitStderr is on (and we're not in a pty, which always combines
+	// the two), stderr gets its own pipe and scanner so lines can be tagged
+	// with their origin stream.
+	var stderrReaderPipe *io.PipeReader
+	var stderrWriterPipe *io.PipeWriter
+	if p.SplitStderr && !p.PTY {
+		stderrReaderPipe, stderrWriterPipe = io.Pipe()
+	}
+
 	var waitGroup sync.WaitGroup
 
 	// Toggle between running in a pty
 	if p.PTY {
-		pty, err := StartPTY(p.command)
-		if err != nil {
-			p.ExitStatus = "1"
+		if err := p.runTTY(lineWriterPipe, &waitGroup); err != nil {
 			return err
 		}
-
-		p.Pid = p.command.Process.Pid
-		p.setRunning(true)
-
-		waitGroup.Add(1)
-
-		go func() {
-			logger.Debug("[Process] Starting to copy PTY to the buffer")
-
-			// Copy the pty to our buffer. This will block until it
-			// EOF's or something breaks.
-			_, err = io.Copy(lineWriterPipe, pty)
-			if e, ok := err.(*os.PathError); ok && e.Err == syscall.EIO {
-				// We can safely ignore this error, because
-				// it's just the PTY telling us that it closed
-				// successfully.  See:
-				// https://github.com/buildkite/agent/pull/34#issuecomment-46080419
-				err = nil
-			}
-
-			if err != nil {
-				logger.Error("[Process] PTY output copy failed with error: %T: %v", err, err)
-			} else {
-				logger.Debug("[Process] PTY has finished being copied to the buffer")
-			}
-
-			waitGroup.Done()
-		}()
 	} else {
-		p.command.Stdout = lineWriterPipe
-		p.command.Stderr = lineWriterPipe
-		p.command.Stdin = nil
-
-		err := p.command.Start()
-		if err != nil {
-			p.ExitStatus = "1"
+		if err := p.runNoTTY(lineWriterPipe, stderrWriterPipe, &waitGroup); err != nil {
 			return err
 		}
-
-		p.Pid = p.command.Process.Pid
-		p.setRunning(true)
 	}
 
 	logger.Info("[Process] Process is running with PID: %d", p.Pid)
 
+	// Only watch ctx now that p.command.Process is actually set, so
+	// p.shutdown() below has a real process to signal. If ctx was already
+	// canceled by the time we get here, ctx.Done() is already closed, so
+	// this select fires immediately instead of missing the cancellation.
+	go func() {
+		select {
+		case <-ctx.Done():
+			logger.Debug("[Process] Context canceled, shutting down PID: %d", p.Pid)
+			if err := p.shutdown(); err != nil {
+				logger.Error("[Process] Failed to shut down process after context cancellation: %v", err)
+			}
+		case <-p.done:
+		}
+	}()
+
 	// Notify other goroutines that are blocked on our Started condition.
 	p.startedCond.L.Unlock()
 	p.startedCond.Broadcast()
 
-	scanner := bufio.NewScanner(lineReaderPipe)
-
-	var lineCallbackWaitGroup sync.WaitGroup
-	waitGroup.Add(1)
-
-	go func() {
-		defer waitGroup.Done()
-
-		// We scan line by line so that we can run our various processors, currently this buffers the entire
-		// output in memory and then an asynchronous process reads it in chunks
-		logger.Debug("[LineScanner] Starting to read lines")
-		for scanner.Scan() {
-			line := scanner.Text()
-			checkedForCallback := false
-			lineHasCallback := false
-			lineString := p.LinePreProcessor(line)
-
-			// Optionally prefix lines with timestamps
-			if p.Timestamp {
-				lineHasCallback = p.LineCallbackFilter(lineString)
-				checkedForCallback = true
-
-				if lineHasCallback || headerExpansionRegex.MatchString(lineString) {
-					// Don't timestamp special lines (e.g. header)
-					p.writeOutputBuffer(fmt.Sprintf("%s\n", line))
-				} else {
-					currentTime := time.Now().UTC().Format(time.RFC3339)
-					p.writeOutputBuffer(fmt.Sprintf("[%s] %s\n", currentTime, line))
-				}
-			} else {
-				p.writeOutputBuffer(line + "\n")
-			}
-
-			// A callback is an async function that is triggered by a line
-			if lineHasCallback || !checkedForCallback {
-				lineCallbackWaitGroup.Add(1)
-				go func(line string) {
-					defer lineCallbackWaitGroup.Done()
-					if (checkedForCallback && lineHasCallback) || p.LineCallbackFilter(lineString) {
-						p.LineCallback(line)
-					}
-				}(lineString)
-			}
-		}
+	p.scanLines(lineReaderPipe, Stdout, &waitGroup)
+	if stderrReaderPipe != nil {
+		p.scanLines(stderrReaderPipe, Stderr, &waitGroup)
+	}
 
-		if err := scanner.Err(); err != nil {
-			logger.Debug("[LineScanner] Error from scanner: %v", err)
-		}
-	}()
+	if p.NoOutputTimeout > 0 {
+		atomic.StoreInt64(&p.lastOutputAt, time.Now().UnixNano())
+		go p.monitorStall()
+	}
 
 	logger.Debug("[LineScanner] Finished")
 
@@ -201,6 +325,9 @@ func (p *Process) Start() error {
 
 	// Close the line writer pipe
 	_ = lineWriterPipe.Close()
+	if stderrWriterPipe != nil {
+		_ = stderrWriterPipe.Close()
+	}
 
 	// The process is no longer running at this point
 	p.setRunning(false)
@@ -221,90 +348,305 @@ func (p *Process) Start() error {
 	return nil
 }
 
-func (p *Process) writeOutputBuffer(s string) {
-	p.bufferLock.Lock()
-	defer p.bufferLock.Unlock()
-	_, _ = p.buffer.WriteString(s)
-}
+// runTTY starts the process attached to a pty, streams Stdin into the pty
+// master, and tees pty output to the line buffer and StdoutWriter.
+func (p *Process) runTTY(lineWriterPipe *io.PipeWriter, waitGroup *sync.WaitGroup) error {
+	pty, err := StartPTY(p.command)
+	if err != nil {
+		p.ExitStatus = "1"
+		return err
+	}
 
-func (p *Process) Output() string {
-	p.bufferLock.Lock()
-	defer p.bufferLock.Unlock()
-	logger.Debug("[Process] Polling for output: (%d bytes)", p.buffer.Len())
-	return p.buffer.String()
+	p.ptyFile = pty
+	p.Pid = p.command.Process.Pid
+	p.setRunning(true)
+
+	if p.Stdin != nil {
+		go func() {
+			if _, err := io.Copy(pty, p.Stdin); err != nil {
+				logger.Debug("[Process] Error copying Stdin to PTY: %v", err)
+			}
+		}()
+	}
+
+	if p.Resize != nil {
+		go func() {
+			for {
+				select {
+				case size, ok := <-p.Resize:
+					if !ok {
+						return
+					}
+					if err := p.WindowSize(size.Rows, size.Cols); err != nil {
+						logger.Error("[Process] Failed to resize PTY: %v", err)
+					}
+				case <-p.done:
+					return
+				}
+			}
+		}()
+	}
+
+	waitGroup.Add(1)
+
+	go func() {
+		logger.Debug("[Process] Starting to copy PTY to the buffer")
+
+		out := io.Writer(lineWriterPipe)
+		if p.StdoutWriter != nil {
+			out = io.MultiWriter(lineWriterPipe, p.StdoutWriter)
+		}
+
+		// Copy the pty to our buffer. This will block until it
+		// EOF's or something breaks.
+		_, err = io.Copy(out, pty)
+		if e, ok := err.(*os.PathError); ok && e.Err == syscall.EIO {
+			// We can safely ignore this error, because
+			// it's just the PTY telling us that it closed
+			// successfully.  See:
+			// https://github.com/buildkite/agent/pull/34#issuecomment-46080419
+			err = nil
+		}
+
+		if err != nil {
+			logger.Error("[Process] PTY output copy failed with error: %T: %v", err, err)
+		} else {
+			logger.Debug("[Process] PTY has finished being copied to the buffer")
+		}
+
+		waitGroup.Done()
+	}()
+
+	return nil
 }
 
-func (p *Process) Kill() error {
-	var err error
-	if runtime.GOOS == "windows" {
-		// Sending Interrupt on Windows is not implemented.
-		// https://golang.org/src/os/exec.go?s=3842:3884#L110
-		err = exec.Command("CMD", "/C", "TASKKILL", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
-	} else {
-		// Send a sigterm
-		err = p.signal(syscall.SIGTERM)
+// runNoTTY starts the process with plain pipes. Stdin is handed straight to
+// exec.Cmd, which falls back to an os.Pipe pair and a copying goroutine when
+// it isn't an *os.File, giving us the same behaviour as the pty path without
+// needing a real terminal. stderrWriterPipe is non-nil when SplitStderr is
+// giving stderr its own pipe rather than sharing lineWriterPipe.
+func (p *Process) runNoTTY(lineWriterPipe *io.PipeWriter, stderrWriterPipe *io.PipeWriter, waitGroup *sync.WaitGroup) error {
+	stdout := io.Writer(lineWriterPipe)
+	if p.StdoutWriter != nil {
+		stdout = io.MultiWriter(lineWriterPipe, p.StdoutWriter)
 	}
-	if err != nil {
+	p.command.Stdout = stdout
+
+	stderrPipe := io.Writer(lineWriterPipe)
+	if stderrWriterPipe != nil {
+		stderrPipe = stderrWriterPipe
+	}
+
+	stderr := stderrPipe
+	if p.StderrWriter != nil {
+		stderr = io.MultiWriter(stderrPipe, p.StderrWriter)
+	}
+	p.command.Stderr = stderr
+
+	p.command.Stdin = p.Stdin
+
+	if err := p.command.Start(); err != nil {
+		p.ExitStatus = "1"
 		return err
 	}
 
-	// Make a channel that we'll use as a timeout
-	c := make(chan int, 1)
-	checking := true
+	p.Pid = p.command.Process.Pid
+	p.setRunning(true)
 
-	// Start a routine that checks to see if the process
-	// is still alive.
-	go func() {
-		for checking {
-			logger.Debug("[Process] Checking to see if PID: %d is still alive", p.Pid)
+	return nil
+}
+
+// WindowSize sets the pty's window size. It's a no-op unless the process was
+// started with PTY enabled. The actual ioctl is platform-specific; see
+// setWindowSize in exec_unix.go/exec_windows.go.
+func (p *Process) WindowSize(rows, cols uint16) error {
+	if !p.PTY || p.ptyFile == nil {
+		return nil
+	}
 
-			foundProcess, err := os.FindProcess(p.Pid)
+	return setWindowSize(p.ptyFile.Fd(), rows, cols)
+}
 
-			// Can't find the process at all
-			if err != nil {
-				logger.Debug("[Process] Could not find process with PID: %d", p.Pid)
+// monitorStall watches for NoOutputTimeout elapsing with no new output
+// dispatched and triggers stall handling exactly once if it does.
+func (p *Process) monitorStall() {
+	interval := p.NoOutputTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
 
-				break
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			last := time.Unix(0, atomic.LoadInt64(&p.lastOutputAt))
+			if p.IsRunning() && time.Since(last) >= p.NoOutputTimeout {
+				p.handleStall()
+				return
 			}
+		}
+	}
+}
 
-			// We have some information about the process
-			if foundProcess != nil {
-				processState, err := foundProcess.Wait()
+// handleStall is called once NoOutputTimeout has elapsed with no output.
+// It SIGQUITs the process group to try to capture a Go-style goroutine
+// dump, gives it a short window to land in the output, then hands off to
+// the normal shutdown escalation ladder. If the process has SIGQUIT
+// blocked, it is sent SIGKILL instead and the ladder is skipped.
+func (p *Process) handleStall() {
+	logger.Error("[Process] No output for %s, PID: %d may be hung", p.NoOutputTimeout, p.Pid)
+
+	if runtime.GOOS != "windows" {
+		sig := syscall.SIGQUIT
+		if isSignalBlocked(p.Pid, sig) {
+			// Mirrors the Go runtime's own sigisblocked fallback: if
+			// SIGQUIT is blocked, a goroutine dump was never going to
+			// happen. The process is already confirmed stalled, so don't
+			// bother with the graceful ladder either - escalate straight
+			// to SIGKILL instead of waiting out a full GracePeriod on a
+			// SIGTERM it's just as likely to ignore.
+			logger.Debug("[Process] SIGQUIT is blocked in PID: %d, skipping goroutine dump and killing", p.Pid)
+
+			if err := p.signalGroup(syscall.SIGKILL); err != nil {
+				logger.Error("[Process] Failed to SIGKILL stalled PID: %d: %v", p.Pid, err)
+			}
 
-				if err != nil || processState.Exited() {
-					logger.Debug("[Process] Process with PID: %d has exited.", p.Pid)
+			return
+		}
 
-					break
-				}
-			}
+		before := len(p.Output())
 
-			// Retry in a moment
-			sleepTime := time.Duration(1 * time.Second)
-			time.Sleep(sleepTime)
+		if err := p.signalGroup(sig); err != nil {
+			logger.Error("[Process] Failed to SIGQUIT stalled PID: %d: %v", p.Pid, err)
+		} else {
+			time.Sleep(hangDiagnosticsWindow)
+
+			if output := p.Output(); len(output) > before {
+				p.setHangDiagnostics([]byte(output[before:]))
+			}
 		}
+	}
 
-		c <- 1
-	}()
+	if err := p.shutdown(); err != nil {
+		logger.Error("[Process] Failed to shut down stalled PID: %d: %v", p.Pid, err)
+	}
+}
 
-	// Timeout this process after 3 seconds
-	select {
-	case _ = <-c:
-		// Was successfully terminated
-	case <-time.After(10 * time.Second):
-		// Stop checking in the routine above
-		checking = false
+// HangDiagnostics returns whatever output was captured in the window after
+// a NoOutputTimeout-triggered SIGQUIT, e.g. a Go goroutine dump, so the
+// caller can attach it to the failed job. It's empty unless a stall was
+// detected.
+func (p *Process) HangDiagnostics() []byte {
+	p.hangDiagnosticsLock.Lock()
+	defer p.hangDiagnosticsLock.Unlock()
+	return p.hangDiagnostics
+}
+
+func (p *Process) setHangDiagnostics(b []byte) {
+	p.hangDiagnosticsLock.Lock()
+	defer p.hangDiagnosticsLock.Unlock()
+	p.hangDiagnostics = b
+}
+
+func (p *Process) Output() string {
+	output := p.defaultSink.Output()
+	logger.Debug("[Process] Polling for output: (%d bytes)", len(output))
+	return output
+}
 
-		// Forcefully kill the thing
-		err = p.signal(syscall.SIGKILL)
+// OutputStreams returns the stdout and stderr output captured so far,
+// separated by origin stream. It's only meaningful when SplitStderr is on;
+// otherwise all output is tagged Stdout and stderr will be empty. The two
+// strings alone don't preserve how the streams were interleaved; use
+// LineEvents if a caller needs to reconstruct that ordering.
+func (p *Process) OutputStreams() (stdout, stderr string) {
+	return p.defaultSink.OutputStreams()
+}
 
-		if err != nil {
+// LineEvents returns a snapshot of every LineEvent dispatched so far, each
+// still carrying the Seq it was stamped with, so a caller reading stdout
+// and stderr separately via OutputStreams can recover the original
+// interleaved order across the two streams.
+func (p *Process) LineEvents() []LineEvent {
+	return p.defaultSink.Events()
+}
+
+// Kill walks the ShutdownPolicy escalation ladder against the process
+// group until the process exits.
+func (p *Process) Kill() error {
+	return p.shutdown()
+}
+
+// shutdown sends ShutdownPolicy.GracefulSignal, then each signal in
+// EscalationSignals in turn, to the whole process group, waiting
+// GracePeriod between steps for the process to exit.
+func (p *Process) shutdown() error {
+	if runtime.GOOS == "windows" {
+		// Sending Interrupt on Windows is not implemented, and there's no
+		// equivalent of a process group to escalate against, so just force
+		// kill the one PID.
+		// https://golang.org/src/os/exec.go?s=3842:3884#L110
+		return exec.Command("CMD", "/C", "TASKKILL", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+	}
+
+	policy := p.ShutdownPolicy
+	signals := append([]os.Signal{policy.GracefulSignal}, policy.EscalationSignals...)
+
+	for i, sig := range signals {
+		if err := p.signalGroup(sig); err != nil {
 			return err
 		}
+
+		// No need to wait after the last signal in the ladder.
+		if i == len(signals)-1 {
+			break
+		}
+
+		if p.waitExit(policy.GracePeriod) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// signalGroup sends sig to the process itself and, where process groups are
+// supported, to its whole process group, so orphaned grandchildren of the
+// script get cleaned up too.
+func (p *Process) signalGroup(sig os.Signal) error {
+	if err := p.signal(sig); err != nil {
+		return err
+	}
+
+	if p.Pid != 0 {
+		if err := signalProcessGroup(p.Pid, sig); err != nil {
+			logger.Debug("[Process] Failed to signal process group %d: %v", p.Pid, err)
+		}
 	}
 
 	return nil
 }
 
+// waitExit polls IsRunning until the process exits or timeout elapses,
+// returning whether it exited in time.
+func (p *Process) waitExit(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if !p.IsRunning() {
+			return true
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return !p.IsRunning()
+}
+
 func (p *Process) signal(sig os.Signal) error {
 	if p.command != nil && p.command.Process != nil {
 		logger.Debug("[Process] Sending signal: %s to PID: %d", sig.String(), p.Pid)
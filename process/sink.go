@@ -0,0 +1,360 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stream identifies which of a process's output streams a LineEvent came
+// from.
+type Stream string
+
+const (
+	Stdout Stream = "stdout"
+	Stderr Stream = "stderr"
+)
+
+// LineEvent is a single line of process output, handed to every registered
+// OutputSink as it's scanned off the wire. Seq is a monotonic counter
+// assigned across both streams, so a consumer reading stdout and stderr
+// separately (see Process.OutputStreams) can still recover the original
+// interleaving order via Process.LineEvents.
+type LineEvent struct {
+	Time     time.Time
+	Stream   Stream
+	Bytes    []byte
+	IsHeader bool
+	Seq      uint64
+}
+
+// OutputSink receives LineEvents as a process runs. Sinks decide for
+// themselves whether/how to apply timestamp prefixing and whether a line
+// should trigger Process.LineCallback; the scanner in StartContext just
+// hands them raw events.
+type OutputSink interface {
+	WriteLine(LineEvent) error
+}
+
+// bufferSink is the default OutputSink, reproducing the historical
+// Process.Output() behaviour: lines are timestamp-prefixed (unless they're
+// headers or already matched the callback filter) and accumulated into an
+// in-memory buffer, and LineCallback is dispatched asynchronously.
+type bufferSink struct {
+	process *Process
+
+	buffer       bytes.Buffer
+	stdoutBuffer bytes.Buffer
+	stderrBuffer bytes.Buffer
+	events       []LineEvent
+	lock         sync.Mutex
+}
+
+func newBufferSink(p *Process) *bufferSink {
+	return &bufferSink{process: p}
+}
+
+func (s *bufferSink) WriteLine(evt LineEvent) error {
+	p := s.process
+	line := string(evt.Bytes)
+	lineString := p.LinePreProcessor(line)
+
+	checkedForCallback := false
+	lineHasCallback := false
+
+	var formatted string
+	if p.Timestamp {
+		lineHasCallback = p.LineCallbackFilter(lineString)
+		checkedForCallback = true
+
+		if lineHasCallback || evt.IsHeader {
+			// Don't timestamp special lines (e.g. header)
+			formatted = fmt.Sprintf("%s\n", line)
+		} else {
+			formatted = fmt.Sprintf("[%s] %s\n", evt.Time.Format(time.RFC3339), line)
+		}
+	} else {
+		formatted = line + "\n"
+	}
+
+	s.lock.Lock()
+	_, _ = s.buffer.WriteString(formatted)
+	if evt.Stream == Stderr {
+		_, _ = s.stderrBuffer.WriteString(formatted)
+	} else {
+		_, _ = s.stdoutBuffer.WriteString(formatted)
+	}
+	s.events = append(s.events, evt)
+	s.lock.Unlock()
+
+	// A callback is an async function that is triggered by a line
+	if lineHasCallback || !checkedForCallback {
+		go func(line string, stream Stream) {
+			if (checkedForCallback && lineHasCallback) || p.LineCallbackFilter(lineString) {
+				p.LineCallback(line, stream)
+			}
+		}(lineString, evt.Stream)
+	}
+
+	return nil
+}
+
+func (s *bufferSink) Output() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buffer.String()
+}
+
+func (s *bufferSink) OutputStreams() (stdout, stderr string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stdoutBuffer.String(), s.stderrBuffer.String()
+}
+
+// Events returns a snapshot of every LineEvent dispatched so far, in the
+// order they were dispatched, each still carrying the Seq it was stamped
+// with.
+func (s *bufferSink) Events() []LineEvent {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	events := make([]LineEvent, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// FileSink writes every LineEvent to an underlying file, one line per
+// write, so a caller can redirect a command's output to its own file
+// instead of (or alongside) the in-memory buffer.
+type FileSink struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+// NewFileSink opens (creating it if necessary, truncating it if it already
+// exists) the file at path and returns an OutputSink that appends every
+// line to it.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{file: f}, nil
+}
+
+func (s *FileSink) WriteLine(evt LineEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, err := s.file.Write(append(append([]byte{}, evt.Bytes...), '\n'))
+	return err
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
+
+// RotatingFileSink writes LineEvents to a file at Path, rotating to
+// Path.1, Path.2, ... once the current file reaches MaxBytes and keeping
+// at most MaxBackups of those around. Useful for long-running jobs whose
+// output would otherwise grow an on-disk log without bound.
+type RotatingFileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	file    *os.File
+	written int64
+	lock    sync.Mutex
+}
+
+// NewRotatingFileSink opens (or creates) the file at path, appending to it
+// if it already exists, and returns an OutputSink that rotates it out to
+// numbered backups once it reaches maxBytes.
+func NewRotatingFileSink(path string, maxBytes int64, maxBackups int) (*RotatingFileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &RotatingFileSink{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+		file:       f,
+		written:    info.Size(),
+	}, nil
+}
+
+func (s *RotatingFileSink) WriteLine(evt LineEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	line := append(append([]byte{}, evt.Bytes...), '\n')
+
+	if s.maxBytes > 0 && s.written+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.written += int64(n)
+	return err
+}
+
+// rotate closes the current file, shuffles path.1..path.N-1 up to
+// path.2..path.N (dropping whatever was at path.N), moves path itself to
+// path.1, and reopens path fresh. Caller must hold s.lock.
+func (s *RotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+		_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	}
+
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		oldPath := fmt.Sprintf("%s.%d", s.path, i)
+		newPath := fmt.Sprintf("%s.%d", s.path, i+1)
+
+		_ = os.Rename(oldPath, newPath)
+	}
+
+	// maxBackups <= 0 means "truncate, don't rotate" - skip stashing the
+	// current file as path.1, since nothing will ever clean it up.
+	if s.maxBackups > 0 {
+		if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	s.file = f
+	s.written = 0
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *RotatingFileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
+
+// jsonLine is the on-the-wire shape JSONLinesSink writes for each
+// LineEvent.
+type jsonLine struct {
+	Time     time.Time `json:"time"`
+	Stream   Stream    `json:"stream"`
+	Text     string    `json:"text"`
+	IsHeader bool      `json:"is_header,omitempty"`
+	Seq      uint64    `json:"seq"`
+}
+
+// JSONLinesSink writes every LineEvent as one line of newline-delimited
+// JSON to an underlying writer, for callers that want to ship structured
+// per-line output to a log aggregator rather than a plain text stream.
+type JSONLinesSink struct {
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+// NewJSONLinesSink returns an OutputSink that writes each LineEvent to w
+// as a line of JSON.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{writer: w}
+}
+
+func (s *JSONLinesSink) WriteLine(evt LineEvent) error {
+	b, err := json.Marshal(jsonLine{
+		Time:     evt.Time,
+		Stream:   evt.Stream,
+		Text:     string(evt.Bytes),
+		IsHeader: evt.IsHeader,
+		Seq:      evt.Seq,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.writer.Write(append(b, '\n'))
+	return err
+}
+
+// UploadChunkSink batches LineEvents into chunks and hands each chunk to
+// Upload once it reaches MaxBytes (the line that fills a chunk is kept
+// whole, so a chunk can run past MaxBytes), instead of holding the whole
+// command's output in memory until completion. This mirrors the way
+// Buildkite ships job logs to the API in chunks as a job runs rather than
+// waiting for it to finish.
+type UploadChunkSink struct {
+	maxBytes int
+	upload   func(chunk []byte, sequence int) error
+
+	buffer   bytes.Buffer
+	sequence int
+	lock     sync.Mutex
+}
+
+// NewUploadChunkSink returns an OutputSink that calls upload with each
+// chunk once the buffered output reaches maxBytes, tagging each chunk with
+// a 1-based sequence number.
+func NewUploadChunkSink(maxBytes int, upload func(chunk []byte, sequence int) error) *UploadChunkSink {
+	return &UploadChunkSink{maxBytes: maxBytes, upload: upload}
+}
+
+func (s *UploadChunkSink) WriteLine(evt LineEvent) error {
+	s.lock.Lock()
+	s.buffer.Write(evt.Bytes)
+	s.buffer.WriteByte('\n')
+	full := s.maxBytes > 0 && s.buffer.Len() >= s.maxBytes
+	s.lock.Unlock()
+
+	if !full {
+		return nil
+	}
+
+	return s.Flush()
+}
+
+// Flush uploads whatever output is currently buffered, even if it hasn't
+// reached maxBytes yet. Callers should call this once after the process
+// exits to ship the final partial chunk.
+func (s *UploadChunkSink) Flush() error {
+	s.lock.Lock()
+	if s.buffer.Len() == 0 {
+		s.lock.Unlock()
+		return nil
+	}
+
+	chunk := make([]byte, s.buffer.Len())
+	copy(chunk, s.buffer.Bytes())
+	s.buffer.Reset()
+	s.sequence++
+	seq := s.sequence
+	s.lock.Unlock()
+
+	return s.upload(chunk, seq)
+}
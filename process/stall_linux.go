@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// isSignalBlocked reports whether pid has sig in its blocked-signal mask,
+// by reading the SigBlk field of /proc/<pid>/status. This is a userspace
+// approximation of the check the Go runtime itself makes (via
+// runtime.sigisblocked) before deciding a SIGQUIT goroutine dump won't land
+// and it should escalate straight to killing the process.
+func isSignalBlocked(pid int, sig syscall.Signal) bool {
+	f, err := os.Open("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "SigBlk:") {
+			continue
+		}
+
+		mask, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "SigBlk:")), 16, 64)
+		if err != nil {
+			return false
+		}
+
+		return mask&(1<<(uint64(sig)-1)) != 0
+	}
+
+	return false
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+import "syscall"
+
+// isSignalBlocked always reports false outside Linux: we have no portable
+// way to inspect another process's signal mask, so we just attempt the
+// SIGQUIT and let the shutdown ladder take over if a dump doesn't land.
+func isSignalBlocked(pid int, sig syscall.Signal) bool {
+	return false
+}